@@ -1,14 +1,15 @@
 package main
 
 import (
-	"bytes"
+	"bufio"
 	"encoding/json"
 	"flag"
-	"io"
-	"io/ioutil"
+	"fmt"
 	"log"
-	"net/http"
-	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -16,45 +17,431 @@ import (
 const (
 	// ApiHost of the slack api.
 	ApiHost = "https://slack.com/api/"
+
+	// dateFlagLayout is the expected layout of --older-than and --newer-than.
+	dateFlagLayout = "2006/01/02"
 )
 
 func main() {
 	token := flag.String("token", "", "Slack user token.")
 	channelID := flag.String("channel", "", "Target channel ID.")
+	channels := flag.String("channels", "", "Comma-separated list of target channel names or IDs (e.g. general,aws-cost,memo). Takes precedence over --channel.")
 	messageTimestamp := flag.String("timestamp", "", "Target message timestamp. If this is not provided, will delete all Messages in the target channelID.")
 	execute := flag.Bool("execute", false, "If you delete messages, please set this flag true. The default mode is dry-run(do not delete messages).")
+	olderThan := flag.String("older-than", "", "Only delete messages older than this date (e.g. 2021/11/28).")
+	newerThan := flag.String("newer-than", "", "Only delete messages newer than this date (e.g. 2021/11/28).")
+	userID := flag.String("user", "", "Only delete messages posted by this user ID.")
+	subtype := flag.String("subtype", "", "Only delete messages with this subtype (e.g. bot_message).")
+	match := flag.String("match", "", "Only delete messages whose text or attachment matches this regexp.")
+	reportPath := flag.String("report", "", "Write a report of every matched message to this path before deleting. Use a .ndjson or .json extension.")
+	replayPath := flag.String("replay", "", "Delete exactly the messages recorded in this --report ndjson file, instead of scanning history.")
+	checkpointPath := flag.String("checkpoint", "", "Persist progress to this file after each delete, and resume from it on restart.")
 	flag.Parse()
 
-	c := newClient(*token, *channelID)
+	f, err := newMessageFilter(*userID, *subtype, *match)
+	if err != nil {
+		log.Fatalf("can't build message filter: %v", err)
+	}
+
+	oldest, latest, err := dateRangeToTimestamps(*newerThan, *olderThan)
+	if err != nil {
+		log.Fatalf("can't parse date range: %v", err)
+	}
 
-	if *messageTimestamp == "" {
-		log.Printf("Will delete all Messages in the channel.")
-		history := c.getMessages("")
-		c.deleteMessages(history, *execute)
-	} else {
-		log.Printf("Will delete the message posted at %s in the channel ID: %s", *messageTimestamp, *channelID)
-		c.deleteMessage(*messageTimestamp, *execute)
+	cp, err := loadCheckpointStore(*checkpointPath)
+	if err != nil {
+		log.Fatalf("can't load checkpoint: %v", err)
+	}
+
+	api := newSlackAPI(*token)
+	c := newClient(api)
+
+	if *replayPath != "" {
+		entries, err := readReplay(*replayPath)
+		if err != nil {
+			log.Fatalf("can't read replay file: %v", err)
+		}
+		for _, e := range entries {
+			log.Printf("replay delete: channel %s, ts %s", e.Channel, e.Timestamp)
+			var files []file
+			for _, id := range e.Files {
+				files = append(files, file{ID: id})
+			}
+			// ThreadTS is left unset so a thread parent's replies are not
+			// fetched and deleted here: each reply has its own entry.
+			c.deleteMessage(e.Channel, &message{Timestamp: e.Timestamp, Files: files}, *execute)
+		}
+		log.Printf("Messages were successfully deleted.")
+		return
+	}
+
+	refs := []string{*channelID}
+	if *channels != "" {
+		refs = strings.Split(*channels, ",")
+	}
+	targets, err := resolveChannels(api, refs)
+	if err != nil {
+		log.Fatalf("can't resolve channels: %v", err)
+	}
+	if len(targets) == 0 {
+		log.Fatalf("no channel specified; use --channel or --channels")
+	}
+
+	if *execute && len(targets) > 1 && !confirmDestructive(targets) {
+		log.Printf("Aborted.")
+		return
+	}
+
+	var report []reportEntry
+	for _, target := range targets {
+		if *messageTimestamp == "" {
+			cursor := ""
+			if resumed, ok := cp.get(target.id); ok {
+				cursor = resumed.Cursor
+				log.Printf("resuming channel %s from checkpoint (last ts %s)", target.label(), resumed.LastTS)
+			}
+			log.Printf("Will delete all matching Messages in the channel: %s", target.label())
+			history := c.getMessages(target.id, cursor, oldest, latest)
+			matched, entries := c.deleteMessages(target.id, history, cursor, f, oldest, latest, *execute, cp)
+			report = append(report, entries...)
+			log.Printf("channel %s: %d message(s) matched.", target.label(), matched)
+		} else {
+			log.Printf("Will delete the message posted at %s in the channel: %s", *messageTimestamp, target.label())
+			msg := c.getMessage(target.id, *messageTimestamp)
+			if msg == nil {
+				log.Fatalf("message %s not found in channel %s", *messageTimestamp, target.label())
+			}
+			report = append(report, newReportEntry(target.id, msg))
+			report = append(report, c.deleteMessage(target.id, msg, *execute)...)
+		}
+	}
+
+	if *reportPath != "" {
+		if err := writeReport(*reportPath, report); err != nil {
+			log.Fatalf("can't write report: %v", err)
+		}
+		log.Printf("Wrote report of %d message(s) to %s", len(report), *reportPath)
 	}
 	log.Printf("Messages were successfully deleted.")
 }
 
-type client struct {
-	token      string
-	channelID  string
-	httpClient *http.Client
+// confirmDestructive asks the user to confirm a destructive, multi-channel
+// operation on stdin, defaulting to "yes" on bare Enter.
+func confirmDestructive(targets []channelTarget) bool {
+	labels := make([]string, len(targets))
+	for i, t := range targets {
+		labels[i] = t.label()
+	}
+	fmt.Printf("Are you sure you want to delete messages of channels %v? (Y/n) ", labels)
+	reader := bufio.NewReader(os.Stdin)
+	answer, err := reader.ReadString('\n')
+	if err != nil {
+		// EOF (closed/non-interactive stdin) must not be read as confirmation.
+		return false
+	}
+	answer = strings.TrimSpace(strings.ToLower(answer))
+	return answer == "" || answer == "y" || answer == "yes"
 }
 
-// newClient builds a slack client using the provided token.
-func newClient(token string, channelID string) *client {
-	s := &client{
-		token:      token,
-		channelID:  channelID,
-		httpClient: &http.Client{},
+// dateRangeToTimestamps converts --newer-than/--older-than (layout: dateFlagLayout)
+// into the oldest/latest query parameters expected by conversations.history,
+// which are Unix timestamps with fractional seconds.
+func dateRangeToTimestamps(newerThan, olderThan string) (oldest string, latest string, err error) {
+	if newerThan != "" {
+		t, err := time.Parse(dateFlagLayout, newerThan)
+		if err != nil {
+			return "", "", err
+		}
+		oldest = strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 6, 64)
+	}
+	if olderThan != "" {
+		t, err := time.Parse(dateFlagLayout, olderThan)
+		if err != nil {
+			return "", "", err
+		}
+		latest = strconv.FormatFloat(float64(t.UnixNano())/1e9, 'f', 6, 64)
 	}
+	return oldest, latest, nil
+}
+
+// messageFilter narrows down which messages deleteMessages actually deletes.
+// An empty/nil field means "don't filter on this".
+type messageFilter struct {
+	userID  string
+	subtype string
+	match   *regexp.Regexp
+}
 
-	return s
+// newMessageFilter builds a messageFilter from the --user/--subtype/--match flags.
+func newMessageFilter(userID, subtype, match string) (*messageFilter, error) {
+	f := &messageFilter{userID: userID, subtype: subtype}
+	if match != "" {
+		re, err := regexp.Compile(match)
+		if err != nil {
+			return nil, err
+		}
+		f.match = re
+	}
+	return f, nil
 }
 
+// matches reports whether the message satisfies every configured filter.
+func (f *messageFilter) matches(m *message) bool {
+	if f == nil {
+		return true
+	}
+	if f.userID != "" && m.UserID != f.userID {
+		return false
+	}
+	if f.subtype != "" && m.Subtype != f.subtype {
+		return false
+	}
+	if f.match != nil && !f.matchesText(m) {
+		return false
+	}
+	return true
+}
+
+// matchesText reports whether the regexp matches message.Text or any
+// attachment's Title/Text.
+func (f *messageFilter) matchesText(m *message) bool {
+	if f.match.MatchString(m.Text) {
+		return true
+	}
+	for _, a := range m.Attachments {
+		if f.match.MatchString(a.Title) || f.match.MatchString(a.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// reportEntry is one line of a --report file: enough to identify a matched
+// message and preview it without re-fetching it from Slack.
+type reportEntry struct {
+	Channel   string   `json:"channel"`
+	Timestamp string   `json:"ts"`
+	UserID    string   `json:"user"`
+	Text      string   `json:"text"`
+	ThreadTS  string   `json:"thread_ts,omitempty"`
+	HasFiles  bool     `json:"has_files"`
+	Files     []string `json:"files,omitempty"`
+	Subtype   string   `json:"subtype,omitempty"`
+}
+
+// newReportEntry builds the reportEntry for a message in channelID.
+func newReportEntry(channelID string, m *message) reportEntry {
+	var fileIDs []string
+	for _, f := range m.Files {
+		fileIDs = append(fileIDs, f.ID)
+	}
+	return reportEntry{
+		Channel:   channelID,
+		Timestamp: m.Timestamp,
+		UserID:    m.UserID,
+		Text:      m.Text,
+		ThreadTS:  m.ThreadTS,
+		HasFiles:  len(m.Files) != 0,
+		Files:     fileIDs,
+		Subtype:   m.Subtype,
+	}
+}
+
+// writeReport writes entries to path as an NDJSON stream (one JSON message
+// per line, for .ndjson) or a single JSON array (for .json).
+func writeReport(path string, entries []reportEntry) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	switch filepath.Ext(path) {
+	case ".ndjson":
+		enc := json.NewEncoder(f)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case ".json":
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	default:
+		return fmt.Errorf("unsupported report extension %q; use .ndjson or .json", filepath.Ext(path))
+	}
+}
+
+// readReplay reads an NDJSON --report file back into reportEntries, for
+// --replay.
+func readReplay(path string) ([]reportEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []reportEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e reportEntry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// checkpoint is a single channel's resume point: the cursor to fetch the
+// page of history from, and the ts of the last message processed on that
+// page.
+type checkpoint struct {
+	Cursor string `json:"cursor"`
+	LastTS string `json:"last_ts"`
+}
+
+// checkpointStore persists --checkpoint state, one checkpoint per channel,
+// flushing the whole file atomically after every update.
+type checkpointStore struct {
+	path string
+	data map[string]checkpoint
+}
+
+// loadCheckpointStore reads path if it exists. An empty path disables
+// checkpointing: get always misses and save/clear are no-ops.
+func loadCheckpointStore(path string) (*checkpointStore, error) {
+	s := &checkpointStore{path: path, data: map[string]checkpoint{}}
+	if path == "" {
+		return s, nil
+	}
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &s.data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// get returns the saved checkpoint for channelID, if any.
+func (s *checkpointStore) get(channelID string) (checkpoint, bool) {
+	cp, ok := s.data[channelID]
+	return cp, ok
+}
+
+// save records cp as the resume point for channelID and flushes to disk.
+func (s *checkpointStore) save(channelID string, cp checkpoint) error {
+	if s.path == "" {
+		return nil
+	}
+	s.data[channelID] = cp
+	return s.flush()
+}
+
+// clear removes channelID's checkpoint, e.g. once it finishes successfully.
+func (s *checkpointStore) clear(channelID string) error {
+	if s.path == "" {
+		return nil
+	}
+	delete(s.data, channelID)
+	return s.flush()
+}
+
+// flush writes the whole store to s.path, via a temp file renamed into
+// place so a crash mid-write can't leave a corrupt checkpoint file.
+func (s *checkpointStore) flush() error {
+	b, err := json.MarshalIndent(s.data, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// channelTarget is a resolved channel to operate on: id is always populated,
+// name is populated when the channel was resolved from a channel name.
+type channelTarget struct {
+	id   string
+	name string
+}
+
+// label returns the best human-readable identifier for log/prompt output.
+func (t channelTarget) label() string {
+	if t.name != "" {
+		return t.name
+	}
+	return t.id
+}
+
+// channelIDPattern matches Slack's conversation ID format, e.g. C0123456789.
+var channelIDPattern = regexp.MustCompile(`^[CGD][A-Z0-9]+$`)
+
+// resolveChannels turns a list of channel names and/or IDs (as given to
+// --channel/--channels) into channelTargets, resolving names to IDs via
+// conversations.list.
+func resolveChannels(api SlackAPI, refs []string) ([]channelTarget, error) {
+	var targets []channelTarget
+	need := map[string]bool{}
+	for _, ref := range refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			continue
+		}
+		if channelIDPattern.MatchString(ref) {
+			targets = append(targets, channelTarget{id: ref})
+			continue
+		}
+		need[ref] = true
+	}
+	if len(need) == 0 {
+		return targets, nil
+	}
+
+	cursor := ""
+	for {
+		list, err := api.ConversationsList(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if !list.Ok {
+			return nil, fmt.Errorf("can't list channels: %s", list.ErrorMessage)
+		}
+		for _, ch := range list.Channels {
+			if need[ch.Name] {
+				targets = append(targets, channelTarget{id: ch.ID, name: ch.Name})
+				delete(need, ch.Name)
+			}
+		}
+		cursor = list.Metadata.NextCursor
+		if cursor == "" || len(need) == 0 {
+			break
+		}
+	}
+	if len(need) != 0 {
+		unresolved := make([]string, 0, len(need))
+		for name := range need {
+			unresolved = append(unresolved, name)
+		}
+		return nil, fmt.Errorf("can't find channel(s): %v", unresolved)
+	}
+	return targets, nil
+}
+
+// attachment is a Slack message attachment, e.g. an unfurled link.
 type attachment struct {
 	Fallback string `json:"fallback"`
 	Text     string `json:"text"`
@@ -62,18 +449,35 @@ type attachment struct {
 	Title    string `json:"title"`
 }
 
+// file is an uploaded file attached to a message.
+type file struct {
+	ID string `json:"id"`
+}
+
+// message is a Slack message as returned by conversations.history/replies.
 type message struct {
-	MessageType string `json:"type"`
-	UserID      string `json:"user"`
-	Text        string `json:"text"`
-	Timestamp   string `json:"ts"`
-	Attachments []attachment
+	MessageType string       `json:"type"`
+	Subtype     string       `json:"subtype"`
+	UserID      string       `json:"user"`
+	Text        string       `json:"text"`
+	Timestamp   string       `json:"ts"`
+	ThreadTS    string       `json:"thread_ts"`
+	Attachments []attachment `json:"attachments"`
+	Files       []file       `json:"files"`
+}
+
+// isThreadParent reports whether the message started a thread that has
+// replies (Slack sets thread_ts == ts on the parent of such a thread).
+func (m *message) isThreadParent() bool {
+	return m.ThreadTS != "" && m.ThreadTS == m.Timestamp
 }
 
 type metadata struct {
 	NextCursor string `json:"next_cursor"`
 }
 
+// conversationHistory is the response shape shared by conversations.history
+// and conversations.replies.
 type conversationHistory struct {
 	Ok           bool       `json:"ok"`
 	Messages     []*message `json:"messages"`
@@ -82,97 +486,208 @@ type conversationHistory struct {
 	ErrorMessage string     `json:"error"`
 }
 
+type channelListEntry struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// conversationList is the response shape of conversations.list.
+type conversationList struct {
+	Ok           bool               `json:"ok"`
+	Channels     []channelListEntry `json:"channels"`
+	Metadata     metadata           `json:"response_metadata"`
+	ErrorMessage string             `json:"error"`
+}
+
+// SlackAPI is the set of Slack Web API calls this tool needs. It is
+// implemented by httpSlackAPI (raw HTTP, the default) and, behind the
+// "slacksdk" build tag, by an implementation backed by nlopes/slack.
+type SlackAPI interface {
+	// ConversationsHistory lists messages in channelID, optionally paginated
+	// by cursor and bounded by oldest/latest. inclusive, when true, includes
+	// messages exactly at the oldest/latest boundary instead of treating it
+	// as exclusive.
+	ConversationsHistory(channelID, cursor, oldest, latest string, inclusive bool) (conversationHistory, error)
+	// ConversationsReplies lists the replies (and parent) of the thread
+	// rooted at threadTS in channelID.
+	ConversationsReplies(channelID, threadTS, cursor string) (conversationHistory, error)
+	// ConversationsList lists the channels visible to the token's user.
+	ConversationsList(cursor string) (conversationList, error)
+	// ChatDelete deletes the message at ts in channelID.
+	ChatDelete(channelID, ts string) error
+	// FilesDelete deletes the uploaded file identified by fileID.
+	FilesDelete(fileID string) error
+}
+
+// client is the business-logic layer on top of a SlackAPI: it knows how to
+// walk history/threads and apply filters, but not how requests are made.
+type client struct {
+	api SlackAPI
+}
+
+// newClient builds a client around the given SlackAPI implementation.
+func newClient(api SlackAPI) *client {
+	return &client{api: api}
+}
+
 // getMessages gets at most 100 Messages which posted on a specific slack channel.
-// This can't call over 50 times in 1 min.
 // If you specify a cursor of message history, you can get Messages from the cursor.
+// oldest/latest, when non-empty, narrow the range to messages posted after/before
+// those Unix timestamps (see dateRangeToTimestamps).
 // See https://api.slack.com/methods/conversations.history
-func (client *client) getMessages(cursor string) conversationHistory {
-	values := url.Values{}
-	values.Add("token", client.token)
-	values.Add("channel", client.channelID)
-	if cursor != "" {
-		values.Add("cursor", cursor)
-	}
-	path := ApiHost + "conversations.history"
-	body := strings.NewReader(values.Encode())
-	res, err := client.postRequest(path, body, "application/x-www-form-urlencoded; charset=UTF-8")
-	// 呼び出し制限のため1秒スリープ
-	time.Sleep(1 * time.Second)
+func (client *client) getMessages(channelID, cursor, oldest, latest string) conversationHistory {
+	history, err := client.api.ConversationsHistory(channelID, cursor, oldest, latest, false)
 	if err != nil {
-		log.Fatalf("can't send request: %v", err)
+		log.Fatalf("can't get messages: %v", err)
 	}
-	defer res.Body.Close()
+	return history
+}
 
-	var history conversationHistory
-	err = json.NewDecoder(res.Body).Decode(&history)
+// getMessage fetches the single message at ts in channelID, with its
+// ThreadTS/Files populated, for the --timestamp single-message path. It
+// returns nil if the message can't be found (e.g. already deleted).
+func (client *client) getMessage(channelID, ts string) *message {
+	history, err := client.api.ConversationsHistory(channelID, "", ts, ts, true)
 	if err != nil {
-		log.Fatalf("can't parse response body: %v", err)
+		log.Fatalf("can't get message: %v", err)
 	}
-	return history
+	if !history.Ok {
+		log.Fatalf("can't get message: %s", history.ErrorMessage)
+	}
+	for _, m := range history.Messages {
+		if m.Timestamp == ts {
+			return m
+		}
+	}
+	return nil
 }
 
-// deleteMessage deletes a message by messageTimestamp.
-// This can't call over 50 times in 1 min.
+// deleteMessage deletes a single message, first deleting its thread replies
+// (if it is a thread parent) and any attached files, since Slack does not
+// cascade either of those on its own. It returns a reportEntry for every
+// deleted reply, so callers building a --report can list them alongside
+// the parent's own entry.
 // See https://api.slack.com/methods/chat.delete
 // See https://api.slack.com/messaging/modifying#deleting
-func (client *client) deleteMessage(messageTimestamp string, execute bool) {
-	if !execute {
-		return
+func (client *client) deleteMessage(channelID string, msg *message, execute bool) []reportEntry {
+	var replyEntries []reportEntry
+	if msg.isThreadParent() {
+		replyEntries = client.deleteReplies(channelID, msg.Timestamp, execute)
 	}
-	b, err := json.Marshal(map[string]string{
-		"channel": client.channelID,
-		"ts":      messageTimestamp,
-	})
-	if err != nil {
-		log.Fatalf("can't create json: %v", err)
+	for _, f := range msg.Files {
+		client.deleteFile(f.ID, execute)
 	}
 
-	path := ApiHost + "chat.delete"
-	body := bytes.NewReader(b)
-	res, err := client.postRequest(path, body, "application/json; charset=UTF-8")
-	// 呼び出し制限のため1秒スリープ
-	time.Sleep(1 * time.Second)
-	if err != nil {
-		log.Fatalf("can't send request: %v", err)
+	if !execute {
+		return replyEntries
+	}
+	if err := client.api.ChatDelete(channelID, msg.Timestamp); err != nil {
+		log.Fatalf("can't delete message: %v", err)
 	}
-	defer res.Body.Close()
+	return replyEntries
+}
 
-	bodyBytes, err := ioutil.ReadAll(res.Body)
-	if err != nil {
-		log.Fatalf("can't parse response body: %v", err)
+// deleteReplies walks conversations.replies for threadTS and deletes every
+// reply (but not the parent itself, which the caller deletes separately),
+// returning a reportEntry for each one deleted.
+func (client *client) deleteReplies(channelID, threadTS string, execute bool) []reportEntry {
+	var entries []reportEntry
+	cursor := ""
+	for {
+		replies, err := client.api.ConversationsReplies(channelID, threadTS, cursor)
+		if err != nil {
+			log.Fatalf("can't get thread replies: %v", err)
+		}
+		if !replies.Ok {
+			log.Fatalf("can't get thread replies: %s", replies.ErrorMessage)
+		}
+		for _, reply := range replies.Messages {
+			if reply.Timestamp == threadTS {
+				// This is the parent, returned as the first reply; the
+				// caller is responsible for deleting it.
+				continue
+			}
+			entries = append(entries, newReportEntry(channelID, reply))
+			for _, f := range reply.Files {
+				client.deleteFile(f.ID, execute)
+			}
+			if execute {
+				if err := client.api.ChatDelete(channelID, reply.Timestamp); err != nil {
+					log.Fatalf("can't delete reply: %v", err)
+				}
+			}
+		}
+		if !replies.HasMore {
+			return entries
+		}
+		cursor = replies.Metadata.NextCursor
 	}
-	log.Printf("body: %s", string(bodyBytes))
 }
 
-func (client *client) deleteMessages(history conversationHistory, execute bool) {
-	if !history.Ok {
-		log.Fatalf("can't get messages: %s", history.ErrorMessage)
+// deleteFile deletes an uploaded file via files.delete.
+func (client *client) deleteFile(fileID string, execute bool) {
+	if !execute {
+		return
+	}
+	if err := client.api.FilesDelete(fileID); err != nil {
+		log.Fatalf("can't delete file: %v", err)
 	}
+}
 
-	for _, message := range history.Messages {
-		if message.Text != "" {
-			log.Printf("delete a message: %s", message.Text)
-		} else if len(message.Attachments) != 0 {
-			log.Printf("delete a message: %s", message.Attachments[0].Title)
+// deleteMessages deletes every message that matches f, starting from
+// history (which was fetched with startCursor) and walking HasMore pages
+// in an explicit loop (rather than recursion) so each page boundary is a
+// clean place to checkpoint. After every successful delete it saves cp's
+// cursor/last-ts for channelID, and clears it once the channel is fully
+// processed. The cursor saved is always the one that fetched the page
+// currently being iterated, not history.Metadata.NextCursor (which points
+// past it) — otherwise resuming would skip straight to the next page and
+// silently lose any unprocessed messages on the current one. It returns
+// the number of top-level messages matched by f, and a reportEntry for
+// each of them plus every thread reply deleted alongside them, for
+// --report/--replay.
+func (client *client) deleteMessages(channelID string, history conversationHistory, startCursor string, f *messageFilter, oldest, latest string, execute bool, cp *checkpointStore) (int, []reportEntry) {
+	var matched []reportEntry
+	matchedCount := 0
+	pageCursor := startCursor
+	for {
+		if !history.Ok {
+			log.Fatalf("can't get messages: %s", history.ErrorMessage)
+		}
+
+		for _, message := range history.Messages {
+			if !f.matches(message) {
+				continue
+			}
+			matched = append(matched, newReportEntry(channelID, message))
+			matchedCount++
+			if message.Text != "" {
+				log.Printf("delete a message: %s", message.Text)
+			} else if len(message.Attachments) != 0 {
+				log.Printf("delete a message: %s", message.Attachments[0].Title)
+			}
+			// 取得したメッセージ一覧を1件ずつ削除
+			matched = append(matched, client.deleteMessage(channelID, message, execute)...)
+			if execute {
+				if err := cp.save(channelID, checkpoint{Cursor: pageCursor, LastTS: message.Timestamp}); err != nil {
+					log.Fatalf("can't save checkpoint: %v", err)
+				}
+			}
 		}
-		// 取得したメッセージ一覧を1件ずつ削除
-		client.deleteMessage(message.Timestamp, execute)
-	}
 
-	// 次のメッセージがあったら次のcursorを見て再度メッセージ取得・削除
-	if history.HasMore {
+		if !history.HasMore {
+			break
+		}
+		// 次のメッセージがあったら次のcursorを見て再度メッセージ取得・削除
 		log.Printf("next cursor: %s", history.Metadata.NextCursor)
-		next := client.getMessages(history.Metadata.NextCursor)
-		client.deleteMessages(next, execute)
+		pageCursor = history.Metadata.NextCursor
+		history = client.getMessages(channelID, pageCursor, oldest, latest)
 	}
-}
 
-func (client *client) postRequest(path string, body io.Reader, contentType string) (*http.Response, error) {
-	req, err := http.NewRequest(http.MethodPost, path, body)
-	if err != nil {
-		log.Fatalf("can't build request: %v", err)
+	if execute {
+		if err := cp.clear(channelID); err != nil {
+			log.Fatalf("can't clear checkpoint: %v", err)
+		}
 	}
-	req.Header.Set("Authorization", "Bearer "+client.token)
-	req.Header.Set("Content-Type", contentType)
-	return client.httpClient.Do(req)
+	return matchedCount, matched
 }
@@ -0,0 +1,239 @@
+//go:build !slacksdk
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	maxRetries  = 5
+	baseBackoff = 500 * time.Millisecond
+	maxBackoff  = 30 * time.Second
+)
+
+// httpSlackAPI implements SlackAPI by calling the Slack Web API directly
+// over HTTP, without depending on a Slack SDK.
+//
+// Each method group is rate-limited independently with its own
+// rate.Limiter, matching Slack's per-method tiers, and every request is
+// retried with exponential backoff on 429/5xx responses.
+type httpSlackAPI struct {
+	token      string
+	httpClient *http.Client
+
+	// limiters, one per Slack rate-limit tier this tool touches.
+	historyLimiter *rate.Limiter // conversations.history, conversations.replies, conversations.list (Tier 3, ~50/min)
+	deleteLimiter  *rate.Limiter // chat.delete (Tier 3, ~50/min)
+	filesLimiter   *rate.Limiter // files.delete (Tier 2, ~20/min)
+}
+
+// newSlackAPI builds the default SlackAPI implementation, backed by raw
+// HTTP calls to the Slack Web API. Build with -tags slacksdk to use the
+// nlopes/slack-backed implementation instead.
+func newSlackAPI(token string) SlackAPI {
+	return &httpSlackAPI{
+		token:          token,
+		httpClient:     &http.Client{},
+		historyLimiter: rate.NewLimiter(rate.Limit(50.0/60.0), 1),
+		deleteLimiter:  rate.NewLimiter(rate.Limit(50.0/60.0), 1),
+		filesLimiter:   rate.NewLimiter(rate.Limit(20.0/60.0), 1),
+	}
+}
+
+func (api *httpSlackAPI) ConversationsHistory(channelID, cursor, oldest, latest string, inclusive bool) (conversationHistory, error) {
+	values := url.Values{}
+	values.Add("channel", channelID)
+	if cursor != "" {
+		values.Add("cursor", cursor)
+	}
+	if oldest != "" {
+		values.Add("oldest", oldest)
+	}
+	if latest != "" {
+		values.Add("latest", latest)
+	}
+	if inclusive {
+		values.Add("inclusive", "1")
+	}
+	var history conversationHistory
+	err := api.post(api.historyLimiter, "conversations.history", values, &history)
+	return history, err
+}
+
+func (api *httpSlackAPI) ConversationsReplies(channelID, threadTS, cursor string) (conversationHistory, error) {
+	values := url.Values{}
+	values.Add("channel", channelID)
+	values.Add("ts", threadTS)
+	if cursor != "" {
+		values.Add("cursor", cursor)
+	}
+	var replies conversationHistory
+	err := api.post(api.historyLimiter, "conversations.replies", values, &replies)
+	return replies, err
+}
+
+func (api *httpSlackAPI) ConversationsList(cursor string) (conversationList, error) {
+	values := url.Values{}
+	values.Add("types", "public_channel,private_channel")
+	if cursor != "" {
+		values.Add("cursor", cursor)
+	}
+	var list conversationList
+	err := api.post(api.historyLimiter, "conversations.list", values, &list)
+	return list, err
+}
+
+func (api *httpSlackAPI) ChatDelete(channelID, ts string) error {
+	b, err := json.Marshal(map[string]string{
+		"channel": channelID,
+		"ts":      ts,
+	})
+	if err != nil {
+		return fmt.Errorf("can't create json: %w", err)
+	}
+	var resp result
+	if err := api.postJSON(api.deleteLimiter, "chat.delete", b, &resp); err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return fmt.Errorf("chat.delete failed: %s", resp.Error)
+	}
+	return nil
+}
+
+func (api *httpSlackAPI) FilesDelete(fileID string) error {
+	values := url.Values{}
+	values.Add("file", fileID)
+	var resp result
+	if err := api.post(api.filesLimiter, "files.delete", values, &resp); err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return fmt.Errorf("files.delete failed: %s", resp.Error)
+	}
+	return nil
+}
+
+// post sends a form-encoded POST request and decodes the JSON response into out.
+func (api *httpSlackAPI) post(limiter *rate.Limiter, method string, values url.Values, out interface{}) error {
+	values.Add("token", api.token)
+	return api.do(limiter, method, []byte(values.Encode()), "application/x-www-form-urlencoded; charset=UTF-8", out)
+}
+
+// postJSON sends a JSON-encoded POST request and decodes the JSON response into out.
+func (api *httpSlackAPI) postJSON(limiter *rate.Limiter, method string, body []byte, out interface{}) error {
+	return api.do(limiter, method, body, "application/json; charset=UTF-8", out)
+}
+
+// result mirrors the fields every Slack Web API response shares, enough to
+// detect the "ok: false, error: ratelimited" case that can come back with
+// a 200 status.
+type result struct {
+	Ok    bool   `json:"ok"`
+	Error string `json:"error"`
+}
+
+// do sends body to method, retrying with exponential backoff and jitter on
+// 429 and 5xx responses, honoring the Retry-After header when present, and
+// finally decodes the JSON response into out.
+func (api *httpSlackAPI) do(limiter *rate.Limiter, method string, body []byte, contentType string, out interface{}) error {
+	path := ApiHost + method
+
+	var bodyBytes []byte
+	for attempt := 0; ; attempt++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			return fmt.Errorf("can't acquire rate limit token: %w", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("can't build request: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+api.token)
+		req.Header.Set("Content-Type", contentType)
+
+		res, err := api.httpClient.Do(req)
+		if err != nil {
+			return fmt.Errorf("can't send request: %w", err)
+		}
+
+		bodyBytes, err = readAndClose(res)
+		if err != nil {
+			return fmt.Errorf("can't read response body: %w", err)
+		}
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			if attempt >= maxRetries {
+				return fmt.Errorf("%s: rate limited after %d retries", method, attempt)
+			}
+			time.Sleep(retryAfter(res.Header.Get("Retry-After"), attempt))
+			continue
+		}
+		if res.StatusCode >= 500 {
+			if attempt >= maxRetries {
+				return fmt.Errorf("%s: server error %d after %d retries", method, res.StatusCode, attempt)
+			}
+			time.Sleep(backoff(attempt))
+			continue
+		}
+
+		var r result
+		if err := json.Unmarshal(bodyBytes, &r); err == nil && !r.Ok && r.Error == "ratelimited" {
+			if attempt >= maxRetries {
+				return fmt.Errorf("%s: rate limited after %d retries", method, attempt)
+			}
+			time.Sleep(backoff(attempt))
+			continue
+		}
+
+		break
+	}
+
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("can't parse response body: %w", err)
+	}
+	return nil
+}
+
+// retryAfter parses the Retry-After header (seconds) Slack sends with 429s,
+// falling back to an exponential backoff if the header is missing or invalid.
+func retryAfter(header string, attempt int) time.Duration {
+	if header == "" {
+		return backoff(attempt)
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil {
+		return backoff(attempt)
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// backoff returns an exponential backoff duration with jitter, capped at maxBackoff.
+func backoff(attempt int) time.Duration {
+	d := baseBackoff * time.Duration(1<<attempt)
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	jitter := time.Duration(rand.Int63n(int64(d) / 2))
+	return d/2 + jitter
+}
+
+func readAndClose(res *http.Response) ([]byte, error) {
+	defer res.Body.Close()
+	buf := new(bytes.Buffer)
+	if _, err := buf.ReadFrom(res.Body); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+//go:build slacksdk
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/nlopes/slack"
+)
+
+// sdkSlackAPI implements SlackAPI on top of github.com/nlopes/slack.
+type sdkSlackAPI struct {
+	client *slack.Client
+}
+
+// newSlackAPI builds a SlackAPI backed by nlopes/slack. Selected by building
+// with -tags slacksdk; the default build uses httpSlackAPI instead.
+func newSlackAPI(token string) SlackAPI {
+	return &sdkSlackAPI{client: slack.New(token)}
+}
+
+func (api *sdkSlackAPI) ConversationsHistory(channelID, cursor, oldest, latest string, inclusive bool) (conversationHistory, error) {
+	resp, err := api.client.GetConversationHistory(&slack.GetConversationHistoryParameters{
+		ChannelID: channelID,
+		Cursor:    cursor,
+		Oldest:    oldest,
+		Latest:    latest,
+		Inclusive: inclusive,
+	})
+	if err != nil {
+		return conversationHistory{}, fmt.Errorf("conversations.history: %w", err)
+	}
+	return toConversationHistory(resp.Messages, resp.HasMore, resp.ResponseMetaData.NextCursor), nil
+}
+
+func (api *sdkSlackAPI) ConversationsReplies(channelID, threadTS, cursor string) (conversationHistory, error) {
+	msgs, hasMore, nextCursor, err := api.client.GetConversationReplies(&slack.GetConversationRepliesParameters{
+		ChannelID: channelID,
+		Timestamp: threadTS,
+		Cursor:    cursor,
+	})
+	if err != nil {
+		return conversationHistory{}, fmt.Errorf("conversations.replies: %w", err)
+	}
+	return toConversationHistory(msgs, hasMore, nextCursor), nil
+}
+
+func (api *sdkSlackAPI) ConversationsList(cursor string) (conversationList, error) {
+	channels, nextCursor, err := api.client.GetConversations(&slack.GetConversationsParameters{
+		Cursor: cursor,
+		Types:  []string{"public_channel", "private_channel"},
+	})
+	if err != nil {
+		return conversationList{}, fmt.Errorf("conversations.list: %w", err)
+	}
+	list := conversationList{
+		Ok:       true,
+		Metadata: metadata{NextCursor: nextCursor},
+	}
+	for _, ch := range channels {
+		list.Channels = append(list.Channels, channelListEntry{ID: ch.ID, Name: ch.Name})
+	}
+	return list, nil
+}
+
+func (api *sdkSlackAPI) ChatDelete(channelID, ts string) error {
+	_, _, err := api.client.DeleteMessage(channelID, ts)
+	if err != nil {
+		return fmt.Errorf("chat.delete: %w", err)
+	}
+	return nil
+}
+
+func (api *sdkSlackAPI) FilesDelete(fileID string) error {
+	if err := api.client.DeleteFile(fileID); err != nil {
+		return fmt.Errorf("files.delete: %w", err)
+	}
+	return nil
+}
+
+// toConversationHistory adapts nlopes/slack's []slack.Message into our
+// transport-agnostic conversationHistory shape.
+func toConversationHistory(msgs []slack.Message, hasMore bool, nextCursor string) conversationHistory {
+	history := conversationHistory{
+		Ok:       true,
+		HasMore:  hasMore,
+		Metadata: metadata{NextCursor: nextCursor},
+	}
+	for i := range msgs {
+		m := &msgs[i]
+		msg := &message{
+			MessageType: m.Type,
+			Subtype:     m.SubType,
+			UserID:      m.User,
+			Text:        m.Text,
+			Timestamp:   m.Timestamp,
+			ThreadTS:    m.ThreadTimestamp,
+		}
+		for _, a := range m.Attachments {
+			msg.Attachments = append(msg.Attachments, attachment{
+				Fallback: a.Fallback,
+				Text:     a.Text,
+				Pretext:  a.Pretext,
+				Title:    a.Title,
+			})
+		}
+		for _, f := range m.Files {
+			msg.Files = append(msg.Files, file{ID: f.ID})
+		}
+		history.Messages = append(history.Messages, msg)
+	}
+	return history
+}